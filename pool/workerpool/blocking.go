@@ -0,0 +1,59 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// submitBlockingRetryInterval is how long SubmitBlocking/SubmitWithContext
+// wait between admission attempts while the pool is at capacity.
+const submitBlockingRetryInterval = time.Millisecond * 20
+
+// SubmitBlocking behaves like Submit, but instead of returning
+// UnableToAddErr when the pool is at capacity, it blocks until a worker or
+// queue slot frees up, or the pool is stopped.
+func (wp *WorkerPool) SubmitBlocking(task func()) error {
+	return wp.SubmitWithContext(context.Background(), task)
+}
+
+// SubmitWithContext behaves like SubmitBlocking, but gives up and returns
+// ctx.Err() if ctx is done before the task can be admitted.
+func (wp *WorkerPool) SubmitWithContext(ctx context.Context, task func()) error {
+	if task == nil {
+		return nil
+	}
+	for {
+		err := wp.Submit(task)
+		if err != UnableToAddErr {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(submitBlockingRetryInterval):
+		}
+	}
+}
+
+// Running returns the number of workers currently executing a task.
+func (wp *WorkerPool) Running() int {
+	return int(atomic.LoadInt32(&wp.currentRunningWorker))
+}
+
+// Waiting returns the number of tasks sitting in the backlog queue because
+// no worker was immediately available to run them.
+func (wp *WorkerPool) Waiting() int {
+	return len(wp.taskQueue)
+}
+
+// Cap returns the maximum number of workers the pool may spawn.
+func (wp *WorkerPool) Cap() int {
+	return int(atomic.LoadInt32(&wp.maxWorkers))
+}
+
+// Free returns how many more workers the pool could still spawn before
+// hitting Cap.
+func (wp *WorkerPool) Free() int {
+	return wp.Cap() - int(atomic.LoadInt32(&wp.currentWorkers))
+}
@@ -0,0 +1,102 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitBlockingWaitsForCapacity(t *testing.T) {
+	wp := NewWithQueueSize(1, 1)
+	defer wp.StopWait()
+
+	release := make(chan struct{})
+	if err := wp.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := wp.Submit(func() {}); err != nil {
+		t.Fatalf("Submit (fill queue): %v", err)
+	}
+
+	var ran int32
+	done := make(chan struct{})
+	go func() {
+		if err := wp.SubmitBlocking(func() { atomic.StoreInt32(&ran, 1) }); err != nil {
+			t.Errorf("SubmitBlocking: %v", err)
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("SubmitBlocking returned before the pool had capacity")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SubmitBlocking never returned after capacity freed up")
+	}
+}
+
+func TestSubmitWithContextCancellation(t *testing.T) {
+	wp := NewWithQueueSize(1, 1)
+	defer wp.StopWait()
+
+	release := make(chan struct{})
+	if err := wp.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if err := wp.Submit(func() {}); err != nil {
+		t.Fatalf("Submit (fill queue): %v", err)
+	}
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	err := wp.SubmitWithContext(ctx, func() {})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("SubmitWithContext: got %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRunningWaitingCapFree(t *testing.T) {
+	wp := NewWithQueueSize(2, 4)
+	defer wp.StopWait()
+
+	if got := wp.Cap(); got != 2 {
+		t.Fatalf("Cap: got %d, want 2", got)
+	}
+
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		if err := wp.Submit(func() { <-release }); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+
+	deadline := time.After(time.Second)
+	for wp.Running() != 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("Running: got %d, want 2", wp.Running())
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if got := wp.Free(); got != 0 {
+		t.Fatalf("Free: got %d, want 0", got)
+	}
+
+	if err := wp.Submit(func() {}); err != nil {
+		t.Fatalf("Submit (queued): %v", err)
+	}
+	if got := wp.Waiting(); got != 1 {
+		t.Fatalf("Waiting: got %d, want 1", got)
+	}
+
+	close(release)
+}
@@ -0,0 +1,92 @@
+package workerpool
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// PanicError wraps a panic recovered from a task submitted through
+// SubmitFuture, so callers can tell a crashed task apart from one that
+// returned an ordinary error.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workerpool: task panicked: %v", e.Value)
+}
+
+// Future is a handle to the eventual result of a task submitted through
+// SubmitFuture.
+type Future[T any] struct {
+	done chan struct{}
+	val  T
+	err  error
+}
+
+func newFuture[T any]() *Future[T] {
+	return &Future[T]{done: make(chan struct{})}
+}
+
+func (f *Future[T]) complete(val T, err error) {
+	f.val = val
+	f.err = err
+	close(f.done)
+}
+
+// Done returns a channel that is closed once the task has finished, whether
+// it returned normally, returned an error, or panicked.
+func (f *Future[T]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get blocks until the task finishes and returns its result.
+func (f *Future[T]) Get() (T, error) {
+	<-f.done
+	return f.val, f.err
+}
+
+// GetWithTimeout blocks until the task finishes or d elapses. ok is false
+// if d elapsed first, in which case val and err are the zero value and nil.
+func (f *Future[T]) GetWithTimeout(d time.Duration) (val T, err error, ok bool) {
+	select {
+	case <-f.done:
+		return f.val, f.err, true
+	case <-time.After(d):
+		var zero T
+		return zero, nil, false
+	}
+}
+
+// SubmitFuture submits task to wp and returns a Future carrying its
+// eventual result. Unlike Submit, a panic inside task is also surfaced to
+// the caller, through Future.Get as a *PanicError. The panic is re-raised
+// after being captured so that
+// wp.runTask's own recover still sees it and drives IncPanicked/
+// PanicHandler/Logger exactly as it would for a panicking plain Submit task.
+func SubmitFuture[T any](wp *WorkerPool, task func() (T, error)) (*Future[T], error) {
+	f := newFuture[T]()
+	err := wp.Submit(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				var zero T
+				f.complete(zero, &PanicError{Value: r, Stack: debug.Stack()})
+				panic(r)
+			}
+		}()
+		val, err := task()
+		f.complete(val, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// SubmitFutureAny is SubmitFuture for callers that would rather not name a
+// type parameter at the call site.
+func SubmitFutureAny(wp *WorkerPool, task func() (any, error)) (*Future[any], error) {
+	return SubmitFuture[any](wp, task)
+}
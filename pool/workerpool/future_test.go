@@ -0,0 +1,96 @@
+package workerpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSubmitFutureGet(t *testing.T) {
+	wp := New(2)
+	defer wp.StopWait()
+
+	f, err := SubmitFuture(wp, func() (int, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatalf("SubmitFuture: %v", err)
+	}
+
+	val, err := f.Get()
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if val != 42 {
+		t.Fatalf("Get: got %d, want 42", val)
+	}
+}
+
+func TestSubmitFutureGetError(t *testing.T) {
+	wp := New(2)
+	defer wp.StopWait()
+
+	wantErr := errors.New("boom")
+	f, err := SubmitFuture(wp, func() (int, error) {
+		return 0, wantErr
+	})
+	if err != nil {
+		t.Fatalf("SubmitFuture: %v", err)
+	}
+
+	_, err = f.Get()
+	if err != wantErr {
+		t.Fatalf("Get: got %v, want %v", err, wantErr)
+	}
+}
+
+func TestSubmitFuturePanicCapture(t *testing.T) {
+	wp := New(2)
+	defer wp.StopWait()
+
+	f, err := SubmitFuture(wp, func() (int, error) {
+		panic("kaboom")
+	})
+	if err != nil {
+		t.Fatalf("SubmitFuture: %v", err)
+	}
+
+	_, err = f.Get()
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Get: got %v, want *PanicError", err)
+	}
+	if panicErr.Value != "kaboom" {
+		t.Fatalf("PanicError.Value: got %v, want %q", panicErr.Value, "kaboom")
+	}
+	if len(panicErr.Stack) == 0 {
+		t.Fatal("PanicError.Stack is empty")
+	}
+}
+
+func TestFutureGetWithTimeout(t *testing.T) {
+	wp := New(1)
+	defer wp.StopWait()
+
+	release := make(chan struct{})
+	f, err := SubmitFuture(wp, func() (int, error) {
+		<-release
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("SubmitFuture: %v", err)
+	}
+
+	if _, _, ok := f.GetWithTimeout(20 * time.Millisecond); ok {
+		t.Fatal("GetWithTimeout: expected timeout before task finished")
+	}
+
+	close(release)
+	val, err, ok := f.GetWithTimeout(time.Second)
+	if !ok {
+		t.Fatal("GetWithTimeout: expected the task to finish within the deadline")
+	}
+	if err != nil || val != 1 {
+		t.Fatalf("GetWithTimeout: got (%d, %v), want (1, nil)", val, err)
+	}
+}
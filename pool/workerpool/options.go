@@ -0,0 +1,76 @@
+package workerpool
+
+import "time"
+
+// Logger is the subset of log.Logger that the pool needs to report
+// otherwise-silent problems, such as a panicking task with no PanicHandler
+// configured. It is satisfied by the standard library's *log.Logger.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+// MetricsSink receives counters and gauges describing pool activity, so
+// callers can wire the pool up to Prometheus, OpenTelemetry, or similar.
+type MetricsSink interface {
+	IncSubmitted()
+	IncCompleted()
+	IncRejected()
+	IncPanicked()
+	SetQueueDepth(n int)
+}
+
+// Option configures a WorkerPool at construction time.
+type Option func(*WorkerPool)
+
+// WithPanicHandler installs fn to be called, instead of silently
+// recovering, whenever a submitted task panics. fn receives the recovered
+// value and the stack trace captured at the point of the panic.
+func WithPanicHandler(fn func(recovered any, stack []byte)) Option {
+	return func(wp *WorkerPool) {
+		wp.panicHandler = fn
+	}
+}
+
+// WithLogger installs l as the pool's logger. It is currently used to
+// report panicking tasks when no PanicHandler has been configured.
+func WithLogger(l Logger) Option {
+	return func(wp *WorkerPool) {
+		wp.logger = l
+	}
+}
+
+// WithMetrics installs m to receive submitted/completed/rejected/panicked
+// counters and queue-depth gauges for the lifetime of the pool.
+func WithMetrics(m MetricsSink) Option {
+	return func(wp *WorkerPool) {
+		wp.metrics = m
+	}
+}
+
+// TaskOption configures a single task submitted through SubmitWithOptions.
+type TaskOption func(*taskConfig)
+
+type taskConfig struct {
+	timeout     time.Duration
+	priority    int
+	hasPriority bool
+}
+
+// WithTaskTimeout makes SubmitWithOptions run the task with a context that
+// is cancelled after d. The task must itself observe ctx.Done()/ctx.Err()
+// to abort early; the pool cannot preempt a running goroutine.
+func WithTaskTimeout(d time.Duration) TaskOption {
+	return func(c *taskConfig) {
+		c.timeout = d
+	}
+}
+
+// WithPriority routes the task through the pool's priority queue instead of
+// its FIFO backlog queue when no worker is immediately available. Higher
+// values run first; tasks with equal priority run in submission order.
+func WithPriority(p int) TaskOption {
+	return func(c *taskConfig) {
+		c.priority = p
+		c.hasPriority = true
+	}
+}
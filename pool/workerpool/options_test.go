@@ -0,0 +1,191 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingMetrics is a MetricsSink that just tallies calls, for tests to
+// assert on.
+type countingMetrics struct {
+	submitted int64
+	completed int64
+	rejected  int64
+	panicked  int64
+	depth     int64
+}
+
+func (m *countingMetrics) IncSubmitted()       { atomic.AddInt64(&m.submitted, 1) }
+func (m *countingMetrics) IncCompleted()       { atomic.AddInt64(&m.completed, 1) }
+func (m *countingMetrics) IncRejected()        { atomic.AddInt64(&m.rejected, 1) }
+func (m *countingMetrics) IncPanicked()        { atomic.AddInt64(&m.panicked, 1) }
+func (m *countingMetrics) SetQueueDepth(n int) { atomic.StoreInt64(&m.depth, int64(n)) }
+
+func TestWithMetricsTracksSubmitAndComplete(t *testing.T) {
+	m := &countingMetrics{}
+	wp := New(2, WithMetrics(m))
+	defer wp.StopWait()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	if err := wp.Submit(func() { wg.Done() }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	wg.Wait()
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt64(&m.completed) != 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("IncCompleted calls: got %d, want 1", atomic.LoadInt64(&m.completed))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	if got := atomic.LoadInt64(&m.submitted); got != 1 {
+		t.Fatalf("IncSubmitted calls: got %d, want 1", got)
+	}
+}
+
+func TestWithPanicHandlerAndMetrics(t *testing.T) {
+	m := &countingMetrics{}
+	var recovered any
+	var mu sync.Mutex
+	wp := New(2,
+		WithMetrics(m),
+		WithPanicHandler(func(r any, stack []byte) {
+			mu.Lock()
+			recovered = r
+			mu.Unlock()
+		}),
+	)
+	defer wp.StopWait()
+
+	if err := wp.Submit(func() { panic("kaboom") }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt64(&m.panicked) != 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("IncPanicked calls: got %d, want 1", atomic.LoadInt64(&m.panicked))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	mu.Lock()
+	got := recovered
+	mu.Unlock()
+	if got != "kaboom" {
+		t.Fatalf("PanicHandler recovered: got %v, want %q", got, "kaboom")
+	}
+	if c := atomic.LoadInt64(&m.completed); c != 0 {
+		t.Fatalf("IncCompleted calls: got %d, want 0", c)
+	}
+}
+
+func TestWithLoggerReportsPanicWithoutHandler(t *testing.T) {
+	logged := make(chan string, 1)
+	wp := New(2, WithLogger(loggerFunc(func(format string, args ...any) {
+		select {
+		case logged <- format:
+		default:
+		}
+	})))
+	defer wp.StopWait()
+
+	if err := wp.Submit(func() { panic("kaboom") }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	select {
+	case <-logged:
+	case <-time.After(time.Second):
+		t.Fatal("Logger was never called for a panicking task")
+	}
+}
+
+// loggerFunc adapts a plain func to the Logger interface.
+type loggerFunc func(format string, args ...any)
+
+func (f loggerFunc) Printf(format string, args ...any) { f(format, args...) }
+
+func TestWithTaskTimeoutCancelsContext(t *testing.T) {
+	wp := New(2)
+	defer wp.StopWait()
+
+	done := make(chan error, 1)
+	err := wp.SubmitWithOptions(func(ctx context.Context) {
+		<-ctx.Done()
+		done <- ctx.Err()
+	}, WithTaskTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("SubmitWithOptions: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("ctx.Err(): got %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("task's context was never cancelled")
+	}
+}
+
+func TestWithPriorityOrdersUnderSaturation(t *testing.T) {
+	wp := NewWithQueueSize(1, 8)
+	defer wp.StopWait()
+
+	release := make(chan struct{})
+	if err := wp.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit (occupy the only worker): %v", err)
+	}
+
+	var mu sync.Mutex
+	var order []int
+	record := func(priority int) func(context.Context) {
+		return func(context.Context) {
+			mu.Lock()
+			order = append(order, priority)
+			mu.Unlock()
+		}
+	}
+
+	// Submitted out of priority order while the single worker is busy, so all three
+	// land in the priority heap; execution should come out high-to-low.
+	for _, p := range []int{1, 3, 2} {
+		if err := wp.SubmitWithOptions(record(p), WithPriority(p)); err != nil {
+			t.Fatalf("SubmitWithOptions(priority=%d): %v", p, err)
+		}
+	}
+
+	close(release)
+
+	deadline := time.After(time.Second)
+	for {
+		mu.Lock()
+		n := len(order)
+		mu.Unlock()
+		if n == 3 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("only %d/3 priority tasks ran", n)
+		case <-time.After(time.Millisecond):
+		}
+	}
+
+	mu.Lock()
+	got := append([]int(nil), order...)
+	mu.Unlock()
+	want := []int{3, 2, 1}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("execution order: got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+)
+
+// prioritizedTask is one entry in a WorkerPool's priority backlog.
+type prioritizedTask struct {
+	fn       func()
+	priority int
+	seq      int64
+}
+
+// priorityHeap is a container/heap.Interface over pending prioritizedTasks:
+// higher priority runs first, and tasks of equal priority run in submission
+// order (seq is assigned by enqueuePriority).
+type priorityHeap []*prioritizedTask
+
+func (h priorityHeap) Len() int { return len(h) }
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *priorityHeap) Push(x any)   { *h = append(*h, x.(*prioritizedTask)) }
+func (h *priorityHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// SubmitWithOptions submits a context-aware task along with per-task
+// options such as WithTaskTimeout or WithPriority. task receives a
+// context.Context it should use to observe cancellation; the pool cannot
+// preempt a running goroutine on its own.
+func (wp *WorkerPool) SubmitWithOptions(task func(ctx context.Context), opts ...TaskOption) error {
+	if task == nil {
+		return nil
+	}
+	cfg := &taskConfig{}
+	for _, o := range opts {
+		o(cfg)
+	}
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if cfg.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, cfg.timeout)
+	}
+	run := func() {
+		if cancel != nil {
+			defer cancel()
+		}
+		task(ctx)
+	}
+
+	if cfg.hasPriority {
+		return wp.submitPriority(run, cfg.priority)
+	}
+	return wp.Submit(run)
+}
+
+// submitPriority is Submit, but falls back to the priority heap instead of
+// the FIFO taskQueue when no worker is immediately available.
+func (wp *WorkerPool) submitPriority(task func(), priority int) error {
+	if wp.isClosed() {
+		return UseClosedPoolErr
+	}
+	if wp.metrics != nil {
+		wp.metrics.IncSubmitted()
+	}
+	if w := wp.popReady(); w != nil {
+		w.ch <- task
+		return nil
+	}
+	if wp.trySpawn(task) {
+		return nil
+	}
+	if wp.enqueuePriority(task, priority) {
+		return nil
+	}
+	if wp.metrics != nil {
+		wp.metrics.IncRejected()
+	}
+	return UnableToAddErr
+}
+
+// enqueuePriority pushes task onto the priority heap, reporting whether it
+// fit. Like enqueue, it takes closeMu for reading so it can never race with
+// stop() draining the heap in StopGetTasks.
+func (wp *WorkerPool) enqueuePriority(task func(), priority int) bool {
+	wp.closeMu.RLock()
+	defer wp.closeMu.RUnlock()
+	if wp.isClosed() {
+		return false
+	}
+
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	if len(wp.priorityQueue)+len(wp.taskQueue) >= cap(wp.taskQueue) {
+		return false
+	}
+	wp.prioritySeq++
+	heap.Push(&wp.priorityQueue, &prioritizedTask{fn: task, priority: priority, seq: wp.prioritySeq})
+	return true
+}
+
+// nextPending pops the highest-priority pending task, preferring the
+// priority heap over the plain FIFO taskQueue, if either has work waiting.
+// It takes closeMu for reading, like enqueue/enqueuePriority, so it can
+// never claim a task that stop()/StopGetTasks is concurrently closing in on
+// to drain unexecuted: once isClosed() is observed, it refuses to hand out
+// any more work, leaving whatever is left in either queue for StopGetTasks.
+func (wp *WorkerPool) nextPending() (func(), bool) {
+	wp.closeMu.RLock()
+	defer wp.closeMu.RUnlock()
+	if wp.isClosed() {
+		return nil, false
+	}
+
+	wp.lock.Lock()
+	if len(wp.priorityQueue) > 0 {
+		pt := heap.Pop(&wp.priorityQueue).(*prioritizedTask)
+		wp.lock.Unlock()
+		return pt.fn, true
+	}
+	wp.lock.Unlock()
+
+	select {
+	case task, ok := <-wp.taskQueue:
+		if !ok {
+			return nil, false
+		}
+		return task, true
+	default:
+		return nil, false
+	}
+}
@@ -1,7 +1,10 @@
 package workerpool
 
 import (
+	"container/heap"
 	"errors"
+	"runtime/debug"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -9,11 +12,9 @@ import (
 type Status int32
 
 const (
-	StatusClose             Status = -1
-	StatusCloseWait         Status = -2
-	StatusClosePretreatment Status = -99
-	StatusDispatchRunning   Status = 1
-	StatusStable            Status = 2
+	StatusClose           Status = -1
+	StatusCloseWait       Status = -2
+	StatusDispatchRunning Status = 1
 
 	DefWorkerIdleTime = time.Second * 10
 
@@ -25,28 +26,54 @@ const (
 var UnableToAddErr = errors.New("the queue is full and cannot be added")
 var UseClosedPoolErr = errors.New("you are using a closed pool")
 
+// workerChan is a single worker's private task channel, plus the time it
+// was last pushed onto the ready stack, read and written via atomic so the
+// janitor can scan lastUseTime without taking the pool lock for every
+// worker it inspects. lastUseTime is a UnixNano timestamp.
+type workerChan struct {
+	lastUseTime int64
+	ch          chan func()
+}
+
 type WorkerPool struct {
 	maxWorkers           int32
 	currentWorkers       int32
 	currentRunningWorker int32
 	workerIdleTime       time.Duration
 	status               Status
-	workerQueue          chan *func()
+
+	lock           sync.Mutex
+	ready          []*workerChan
+	workerChanPool sync.Pool
+	priorityQueue  priorityHeap
+	prioritySeq    int64
+
+	// closeMu guards against a Submit/submitPriority send racing with stop()
+	// closing taskQueue: every send takes the read lock, and stop() takes
+	// the write lock around the close so no send can land on a closed
+	// channel and no close can happen while a send is in flight.
+	closeMu   sync.RWMutex
+	taskQueue chan func()
+	stopCh    chan struct{}
+
+	panicHandler func(recovered any, stack []byte)
+	logger       Logger
+	metrics      MetricsSink
 }
 
-func New(maxWorkers int) *WorkerPool {
-	return newPool(maxWorkers, DefQueueSize, DefWorkerIdleTime)
+func New(maxWorkers int, opts ...Option) *WorkerPool {
+	return newPool(maxWorkers, DefQueueSize, DefWorkerIdleTime, opts...)
 }
 
-func NewWithQueueSize(maxWorkers, queueSize int) *WorkerPool {
-	return newPool(maxWorkers, queueSize, DefWorkerIdleTime)
+func NewWithQueueSize(maxWorkers, queueSize int, opts ...Option) *WorkerPool {
+	return newPool(maxWorkers, queueSize, DefWorkerIdleTime, opts...)
 }
 
-func NewWithQueueSizeAndIdleTime(maxWorkers, queueSize int, idle time.Duration) *WorkerPool {
-	return newPool(maxWorkers, queueSize, idle)
+func NewWithQueueSizeAndIdleTime(maxWorkers, queueSize int, idle time.Duration, opts ...Option) *WorkerPool {
+	return newPool(maxWorkers, queueSize, idle, opts...)
 }
 
-func newPool(maxWorkers, queueSize int, idle time.Duration) *WorkerPool {
+func newPool(maxWorkers, queueSize int, idle time.Duration, opts ...Option) *WorkerPool {
 	if maxWorkers < 1 {
 		maxWorkers = 1
 	}
@@ -61,12 +88,18 @@ func newPool(maxWorkers, queueSize int, idle time.Duration) *WorkerPool {
 	}
 	pool := &WorkerPool{
 		maxWorkers:     int32(maxWorkers),
-		currentWorkers: 0,
 		workerIdleTime: idle,
 		status:         StatusDispatchRunning,
-		workerQueue:    make(chan *func(), queueSize),
+		taskQueue:      make(chan func(), queueSize),
+		stopCh:         make(chan struct{}),
 	}
-	go pool.dispatch()
+	pool.workerChanPool.New = func() interface{} {
+		return &workerChan{ch: make(chan func())}
+	}
+	for _, o := range opts {
+		o(pool)
+	}
+	go pool.janitor()
 	return pool
 }
 
@@ -74,97 +107,200 @@ func (wp *WorkerPool) Submit(task func()) error {
 	if task == nil {
 		return nil
 	}
-	if wp.status == StatusClosePretreatment || wp.status == StatusClose || wp.status == StatusCloseWait {
+	if wp.isClosed() {
 		return UseClosedPoolErr
 	}
+	if wp.metrics != nil {
+		wp.metrics.IncSubmitted()
+	}
+	if w := wp.popReady(); w != nil {
+		w.ch <- task
+		return nil
+	}
+	if wp.trySpawn(task) {
+		return nil
+	}
+	if wp.enqueue(task) {
+		return nil
+	}
+	if wp.metrics != nil {
+		wp.metrics.IncRejected()
+	}
+	return UnableToAddErr
+}
+
+// isClosed reports whether the pool has begun (or finished) shutting down.
+func (wp *WorkerPool) isClosed() bool {
+	switch Status(atomic.LoadInt32((*int32)(&wp.status))) {
+	case StatusClose, StatusCloseWait:
+		return true
+	default:
+		return false
+	}
+}
+
+// enqueue hands task to the backlog queue, reporting whether it fit. It
+// takes closeMu for reading so it can never race with stop() closing
+// taskQueue: either enqueue observes the pool is still open and its send
+// happens-before the close, or it observes the pool closed and does not
+// send at all.
+func (wp *WorkerPool) enqueue(task func()) bool {
+	wp.closeMu.RLock()
+	defer wp.closeMu.RUnlock()
+	if wp.isClosed() {
+		return false
+	}
 	select {
-	case wp.workerQueue <- &task:
+	case wp.taskQueue <- task:
+		return true
 	default:
-		return UnableToAddErr
+		return false
 	}
-	return nil
 }
 
 func (wp *WorkerPool) SubmitWait(task func()) error {
 	if task == nil {
 		return nil
 	}
-	if wp.status == StatusClosePretreatment || wp.status == StatusClose || wp.status == StatusCloseWait {
-		return UseClosedPoolErr
-	}
-	doneChan := make(chan bool)
-	var doneFunc = func() {
+	doneChan := make(chan struct{})
+	wrapped := func() {
 		task()
 		close(doneChan)
 	}
-	select {
-	case wp.workerQueue <- &doneFunc:
-		<-doneChan
-	default:
-		return UnableToAddErr
+	if err := wp.Submit(wrapped); err != nil {
+		return err
 	}
+	<-doneChan
 	return nil
 }
 
-func (wp *WorkerPool) dispatch() {
-LOOP:
-	for atomic.LoadInt32((*int32)(&wp.status)) == int32(StatusDispatchRunning) &&
-		atomic.LoadInt32(&wp.currentWorkers) < wp.maxWorkers {
-		select {
-		case tk, ok := <-wp.workerQueue:
-			if !ok {
-				break LOOP
-			}
-			atomic.AddInt32(&wp.currentWorkers, 1)
-			go wp.worker(tk)
-		}
+// popReady pops the most recently used ready worker off the top of the
+// stack, if any. Handing work to the hottest worker keeps its goroutine
+// stack and CPU caches warm instead of round-robining across every worker.
+func (wp *WorkerPool) popReady() *workerChan {
+	wp.lock.Lock()
+	n := len(wp.ready)
+	if n == 0 {
+		wp.lock.Unlock()
+		return nil
 	}
+	w := wp.ready[n-1]
+	wp.ready[n-1] = nil
+	wp.ready = wp.ready[:n-1]
+	wp.lock.Unlock()
+	return w
 }
 
-func (wp *WorkerPool) worker(tk *func()) {
-	(*tk)()
-	if wp.doWorker() {
-		atomic.AddInt32(&wp.currentWorkers, -1)
+// trySpawn starts a new worker goroutine to run task if the pool has not
+// yet reached maxWorkers, reporting whether it did so. It re-checks
+// isClosed so a Stop racing in right after Submit's own check can't cause a
+// brand-new worker to be spawned after the pool has reported itself closed.
+func (wp *WorkerPool) trySpawn(task func()) bool {
+	if wp.isClosed() {
+		return false
 	}
+	for {
+		cur := atomic.LoadInt32(&wp.currentWorkers)
+		if cur >= wp.maxWorkers {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&wp.currentWorkers, cur, cur+1) {
+			break
+		}
+	}
+	w := wp.workerChanPool.Get().(*workerChan)
+	go wp.workerLoop(w, task)
+	return true
 }
 
-// ReadyQueueLength 获取Queue的长度 此方法并不是线程安全的。
-func (wp *WorkerPool) ReadyQueueLength() int {
-	return len(wp.workerQueue)
-}
-
-func (wp *WorkerPool) doWorker() bool {
-	if wp.workerIdleTime > 0 {
-		idle := time.NewTimer(wp.workerIdleTime)
-		for atomic.LoadInt32((*int32)(&wp.status)) != int32(StatusClose) {
-			select {
-			case task, ok := <-wp.workerQueue:
-				if !ok {
-					break
-				}
-				(*task)()
-				idle.Reset(wp.workerIdleTime)
-			case <-idle.C:
-				if atomic.LoadInt32((*int32)(&wp.status)) <= wp.maxWorkers-1 &&
-					atomic.CompareAndSwapInt32((*int32)(&wp.status), int32(StatusStable), int32(StatusDispatchRunning)) {
-
-					atomic.AddInt32(&wp.currentWorkers, -1)
-					wp.dispatch()
-					return false
-				}
-				break
-			}
+// workerLoop runs task, then keeps the worker alive on w.ch: it prefers
+// draining a pending task over going back to sleep, and otherwise pushes
+// itself onto the ready stack and blocks for its next task. It exits when
+// it receives a nil task, which the janitor sends to idle workers.
+func (wp *WorkerPool) workerLoop(w *workerChan, task func()) {
+	for task != nil {
+		atomic.AddInt32(&wp.currentRunningWorker, 1)
+		wp.runTask(task)
+		atomic.AddInt32(&wp.currentRunningWorker, -1)
+
+		if next, ok := wp.nextPending(); ok {
+			task = next
+			continue
+		}
+
+		atomic.StoreInt64(&w.lastUseTime, time.Now().UnixNano())
+		wp.lock.Lock()
+		if wp.isClosed() {
+			// stop() may have already taken (or be about to take) a
+			// snapshot of wp.ready to flush; pushing onto it now would race
+			// with that snapshot and could leave this worker blocked on
+			// w.ch forever with nobody left to send it a wakeup. Exit
+			// instead of going idle.
+			wp.lock.Unlock()
+			task = nil
+			continue
 		}
-	} else {
-		for atomic.LoadInt32((*int32)(&wp.status)) != int32(StatusClose) {
-			task, ok := <-wp.workerQueue
-			if !ok {
-				break
+		wp.ready = append(wp.ready, w)
+		wp.lock.Unlock()
+
+		task = <-w.ch
+	}
+	atomic.AddInt32(&wp.currentWorkers, -1)
+	wp.workerChanPool.Put(w)
+}
+
+// janitor periodically retires workers that have been idle for longer than
+// workerIdleTime, so currentWorkers shrinks back down to the pool's actual
+// load instead of staying pinned at its historical peak. It walks the ready
+// stack from the bottom, where the least recently used workers accumulate,
+// leaving the warmest ones untouched. It exits once the pool is stopped.
+func (wp *WorkerPool) janitor() {
+	interval := wp.workerIdleTime / 2
+	if interval <= 0 {
+		interval = DefWorkerIdleTime / 2
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			wp.retireIdleWorkers()
+			if wp.metrics != nil {
+				wp.lock.Lock()
+				depth := wp.ReadyQueueLength() + len(wp.priorityQueue)
+				wp.lock.Unlock()
+				wp.metrics.SetQueueDepth(depth)
 			}
-			(*task)()
+		case <-wp.stopCh:
+			return
 		}
 	}
-	return true
+}
+
+func (wp *WorkerPool) retireIdleWorkers() {
+	if wp.workerIdleTime <= 0 {
+		return
+	}
+	deadline := time.Now().Add(-wp.workerIdleTime).UnixNano()
+
+	wp.lock.Lock()
+	n := len(wp.ready)
+	i := 0
+	for i < n && atomic.LoadInt64(&wp.ready[i].lastUseTime) < deadline {
+		i++
+	}
+	stale := append([]*workerChan(nil), wp.ready[:i]...)
+	wp.ready = wp.ready[i:]
+	wp.lock.Unlock()
+
+	for _, w := range stale {
+		w.ch <- nil
+	}
+}
+
+// ReadyQueueLength 获取排队中待执行任务的数量，此方法并不是线程安全的。
+func (wp *WorkerPool) ReadyQueueLength() int {
+	return len(wp.taskQueue)
 }
 
 func (wp *WorkerPool) Stop() {
@@ -191,21 +327,76 @@ func (wp *WorkerPool) Status() Status {
 
 func (wp *WorkerPool) StopGetTasks() []func() {
 	wp.stop(StatusClose)
-	tasks := make([]func(), 0, wp.ReadyQueueLength())
-	for task := range wp.workerQueue {
-		tasks = append(tasks, *task)
+	// If another Stop/StopWait call won the transition, wait for it to
+	// finish closing taskQueue and stopCh before draining.
+	<-wp.stopCh
+	// stop() has already closed taskQueue and, under closeMu, guaranteed no
+	// Submit/submitPriority call is still in flight, so it is now safe to
+	// drain both queues without anyone racing to add to them. priorityQueue
+	// is also touched by nextPending/enqueuePriority under wp.lock, so it
+	// must be drained under that same lock, not just closeMu.
+	wp.closeMu.Lock()
+	defer wp.closeMu.Unlock()
+	wp.lock.Lock()
+	defer wp.lock.Unlock()
+	tasks := make([]func(), 0, wp.ReadyQueueLength()+len(wp.priorityQueue))
+	for task := range wp.taskQueue {
+		tasks = append(tasks, task)
+	}
+	for len(wp.priorityQueue) > 0 {
+		tasks = append(tasks, heap.Pop(&wp.priorityQueue).(*prioritizedTask).fn)
 	}
 	return tasks
 }
 
+// runTask executes task, recovering any panic so a single bad task cannot
+// crash the worker goroutine (and with it, the process). The panic is
+// reported through wp.panicHandler if one is configured, falling back to
+// wp.logger, and otherwise silently swallowed. Callers that need to observe
+// the panic in the caller's own goroutine should go through SubmitFuture,
+// which captures it in the returned Future regardless of these hooks.
+func (wp *WorkerPool) runTask(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if wp.metrics != nil {
+				wp.metrics.IncPanicked()
+			}
+			stack := debug.Stack()
+			switch {
+			case wp.panicHandler != nil:
+				wp.panicHandler(r, stack)
+			case wp.logger != nil:
+				wp.logger.Printf("workerpool: task panicked: %v\n%s", r, stack)
+			}
+		} else if wp.metrics != nil {
+			wp.metrics.IncCompleted()
+		}
+	}()
+	task()
+}
+
+// stop transitions the pool from StatusDispatchRunning to status with a
+// single CompareAndSwap, so at most one caller ever wins the transition no
+// matter how many goroutines call Stop/StopWait/StopGetTasks concurrently.
+// The winner, and only the winner, closes taskQueue and stopCh exactly
+// once and flushes every currently idle worker so shutdown doesn't have to
+// wait on the janitor's idle timeout.
 func (wp *WorkerPool) stop(status Status) bool {
-	if atomic.LoadInt32((*int32)(&wp.status)) == int32(StatusClose) || atomic.LoadInt32((*int32)(&wp.status)) == int32(StatusClose) {
+	if !atomic.CompareAndSwapInt32((*int32)(&wp.status), int32(StatusDispatchRunning), int32(status)) {
 		return false
 	}
-	atomic.StoreInt32((*int32)(&wp.status), int32(StatusClosePretreatment))
-	if atomic.CompareAndSwapInt32((*int32)(&wp.status), int32(StatusClosePretreatment), int32(status)) {
-		close(wp.workerQueue)
-		return true
+
+	wp.closeMu.Lock()
+	close(wp.taskQueue)
+	wp.closeMu.Unlock()
+	close(wp.stopCh)
+
+	wp.lock.Lock()
+	idle := wp.ready
+	wp.ready = nil
+	wp.lock.Unlock()
+	for _, w := range idle {
+		w.ch <- nil
 	}
-	return false
-}
\ No newline at end of file
+	return true
+}
@@ -0,0 +1,103 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentSubmitAndShutdown exercises each of Stop, StopWait, and
+// StopGetTasks racing against a burst of concurrent Submit calls. Run with
+// -race: a regression here should show up either as the race detector
+// flagging a data race or as a shutdown call missing its per-iteration
+// deadline.
+func TestConcurrentSubmitAndShutdown(t *testing.T) {
+	shutdowns := map[string]func(*WorkerPool){
+		"Stop":         func(wp *WorkerPool) { wp.Stop() },
+		"StopWait":     func(wp *WorkerPool) { wp.StopWait() },
+		"StopGetTasks": func(wp *WorkerPool) { wp.StopGetTasks() },
+	}
+
+	for name, shutdown := range shutdowns {
+		shutdown := shutdown
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				wp := NewWithQueueSizeAndIdleTime(8, 64, time.Hour)
+
+				var wg sync.WaitGroup
+				stop := make(chan struct{})
+				for w := 0; w < 8; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for {
+							select {
+							case <-stop:
+								return
+							default:
+								_ = wp.Submit(func() {})
+							}
+						}
+					}()
+				}
+
+				done := make(chan struct{})
+				go func() {
+					shutdown(wp)
+					close(done)
+				}()
+
+				select {
+				case <-done:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("%s deadlocked on iteration %d", name, i)
+				}
+
+				close(stop)
+				wg.Wait()
+			}
+		})
+	}
+}
+
+// TestStopGetTasksRaceWithPrioritySubmit races StopGetTasks against
+// SubmitWithOptions(WithPriority(...)), which shares the priority heap that
+// StopGetTasks drains. It should be run with -race.
+func TestStopGetTasksRaceWithPrioritySubmit(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		wp := NewWithQueueSizeAndIdleTime(4, 64, time.Hour)
+
+		var wg sync.WaitGroup
+		stop := make(chan struct{})
+		for w := 0; w < 4; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						_ = wp.SubmitWithOptions(func(ctx context.Context) {}, WithPriority(1))
+					}
+				}
+			}()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wp.StopGetTasks()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("StopGetTasks deadlocked on iteration %d", i)
+		}
+
+		close(stop)
+		wg.Wait()
+	}
+}